@@ -0,0 +1,112 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package api defines the kubeconfig data types loaded by the config package,
+// mirroring the shape of client-go's clientcmd kubeconfig format.
+package api
+
+// Config is the top level kubeconfig object.
+type Config struct {
+	CurrentContext string          `json:"current-context" yaml:"current-context"`
+	Clusters       []NamedCluster  `json:"clusters" yaml:"clusters"`
+	AuthInfos      []NamedAuthInfo `json:"users" yaml:"users"`
+	Contexts       []NamedContext  `json:"contexts" yaml:"contexts"`
+}
+
+// NamedCluster associates a Cluster with the name used to refer to it from a Context.
+type NamedCluster struct {
+	Name    string  `json:"name" yaml:"name"`
+	Cluster Cluster `json:"cluster" yaml:"cluster"`
+}
+
+// Cluster holds the information needed to connect to a remote kubernetes cluster.
+type Cluster struct {
+	Server                   string `json:"server" yaml:"server"`
+	InsecureSkipTLSVerify    bool   `json:"insecure-skip-tls-verify,omitempty" yaml:"insecure-skip-tls-verify,omitempty"`
+	CertificateAuthority     string `json:"certificate-authority,omitempty" yaml:"certificate-authority,omitempty"`
+	CertificateAuthorityData []byte `json:"certificate-authority-data,omitempty" yaml:"certificate-authority-data,omitempty"`
+}
+
+// NamedContext associates a Context with the name used to refer to it.
+type NamedContext struct {
+	Name    string  `json:"name" yaml:"name"`
+	Context Context `json:"context" yaml:"context"`
+}
+
+// Context is a tuple of references to a cluster, a user, and an optional default namespace.
+type Context struct {
+	Cluster   string `json:"cluster" yaml:"cluster"`
+	AuthInfo  string `json:"user" yaml:"user"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+}
+
+// NamedAuthInfo associates an AuthInfo with the name used to refer to it from a Context.
+type NamedAuthInfo struct {
+	Name     string   `json:"name" yaml:"name"`
+	AuthInfo AuthInfo `json:"user" yaml:"user"`
+}
+
+// AuthInfo holds the information needed to authenticate as a particular user.
+type AuthInfo struct {
+	ClientCertificate     string              `json:"client-certificate,omitempty" yaml:"client-certificate,omitempty"`
+	ClientCertificateData []byte              `json:"client-certificate-data,omitempty" yaml:"client-certificate-data,omitempty"`
+	ClientKey             string              `json:"client-key,omitempty" yaml:"client-key,omitempty"`
+	ClientKeyData         []byte              `json:"client-key-data,omitempty" yaml:"client-key-data,omitempty"`
+	Token                 string              `json:"token,omitempty" yaml:"token,omitempty"`
+	TokenFile             string              `json:"tokenFile,omitempty" yaml:"tokenFile,omitempty"`
+	Username              string              `json:"username,omitempty" yaml:"username,omitempty"`
+	Password              string              `json:"password,omitempty" yaml:"password,omitempty"`
+	AuthProvider          *AuthProviderConfig `json:"auth-provider,omitempty" yaml:"auth-provider,omitempty"`
+	// Exec, when set, instructs the client to obtain credentials by running the named
+	// plugin binary rather than using a static token or AuthProvider. It follows the
+	// same client.authentication.k8s.io ExecCredential contract as client-go.
+	Exec *ExecConfig `json:"exec,omitempty" yaml:"exec,omitempty"`
+}
+
+// AuthProviderConfig identifies a pluggable AuthProviderLoader (e.g. "gcp", "oidc") and
+// carries its persisted, provider-specific configuration.
+type AuthProviderConfig struct {
+	Name   string            `json:"name" yaml:"name"`
+	Config map[string]string `json:"config" yaml:"config"`
+}
+
+// ExecConfig specifies a command to run to obtain credentials, matching client-go's
+// exec credential plugin contract (client.authentication.k8s.io).
+type ExecConfig struct {
+	// Command is the path of the plugin binary to execute.
+	Command string `json:"command" yaml:"command"`
+	// Args are passed to Command on invocation.
+	Args []string `json:"args,omitempty" yaml:"args,omitempty"`
+	// Env are additional environment variables to set before invoking Command.
+	Env []ExecEnvVar `json:"env,omitempty" yaml:"env,omitempty"`
+	// APIVersion of the ExecCredential the plugin should be asked to produce, e.g.
+	// "client.authentication.k8s.io/v1beta1" or "client.authentication.k8s.io/v1".
+	APIVersion string `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	// InstallHint is printed to the user if the plugin cannot be found.
+	InstallHint string `json:"installHint,omitempty" yaml:"installHint,omitempty"`
+	// ProvideClusterInfo instructs the client to pass cluster information to the plugin
+	// via the ExecCredential request.
+	ProvideClusterInfo bool `json:"provideClusterInfo,omitempty" yaml:"provideClusterInfo,omitempty"`
+	// InteractiveMode controls whether the plugin may prompt the user (Never, IfAvailable,
+	// Always).
+	InteractiveMode string `json:"interactiveMode,omitempty" yaml:"interactiveMode,omitempty"`
+}
+
+// ExecEnvVar is a name/value pair passed to an exec plugin's environment.
+type ExecEnvVar struct {
+	Name  string `json:"name" yaml:"name"`
+	Value string `json:"value" yaml:"value"`
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// CachedCredential is the token (or client certificate) a CredentialCache stores for a
+// single AuthProvider/exec plugin invocation.
+type CachedCredential struct {
+	Token      string    `yaml:"token,omitempty"`
+	ClientCert string    `yaml:"clientCert,omitempty"`
+	ClientKey  string    `yaml:"clientKey,omitempty"`
+	Expiry     time.Time `yaml:"expiry,omitempty"`
+}
+
+// CredentialCache persists provider credentials across KubeConfigLoader invocations, so
+// providers that are slow or prompt interactively (gcloud, exec plugins) aren't invoked
+// on every load.
+type CredentialCache interface {
+	// Get returns the cached credential for key. ok is false on a cache miss or a stale
+	// entry (one that's expired, or within expirySkewPreventionDelay of expiring).
+	Get(key string) (cred *CachedCredential, ok bool, err error)
+	// Set stores cred under key, replacing any existing entry.
+	Set(key string, cred *CachedCredential) error
+}
+
+// credentialCacheKey derives a stable cache key from the active context's AuthInfo name,
+// the auth provider's name, and a provider-specific discriminator (e.g. an oidc
+// issuer/client-id, or an exec plugin's command) that distinguishes multiple AuthInfos
+// using the same provider.
+func credentialCacheKey(authInfoName, providerName, discriminator string) string {
+	sum := sha256.Sum256([]byte(authInfoName + "\x00" + providerName + "\x00" + discriminator))
+	return hex.EncodeToString(sum[:])
+}
+
+// FileCredentialCache is the default CredentialCache, backed by a single yaml file
+// under $XDG_CACHE_HOME/k8s-go/credentials.yaml (mode 0600). Reads and writes are
+// protected by an flock on a sibling lock file, so concurrent KubeConfigLoaders sharing
+// a cache file don't corrupt it.
+type FileCredentialCache struct {
+	path  string
+	clock func() time.Time
+
+	mu sync.Mutex
+}
+
+// FileCredentialCacheOption configures a FileCredentialCache.
+type FileCredentialCacheOption func(*FileCredentialCache)
+
+// WithCredentialCachePath overrides the cache file location, e.g. to a t.TempDir() in
+// tests.
+func WithCredentialCachePath(path string) FileCredentialCacheOption {
+	return func(c *FileCredentialCache) {
+		c.path = path
+	}
+}
+
+// WithCredentialCacheClock overrides the clock used to evaluate entry expiry, e.g. with
+// a fake clock in tests.
+func WithCredentialCacheClock(clock func() time.Time) FileCredentialCacheOption {
+	return func(c *FileCredentialCache) {
+		c.clock = clock
+	}
+}
+
+// NewFileCredentialCache constructs a FileCredentialCache rooted, by default, at
+// $XDG_CACHE_HOME/k8s-go/credentials.yaml (or ~/.cache/k8s-go/credentials.yaml if
+// XDG_CACHE_HOME is unset).
+func NewFileCredentialCache(opts ...FileCredentialCacheOption) (*FileCredentialCache, error) {
+	path, err := defaultCredentialCachePath()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &FileCredentialCache{path: path, clock: time.Now}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+func defaultCredentialCachePath() (string, error) {
+	base := os.Getenv("XDG_CACHE_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to determine cache directory: %v", err)
+		}
+		base = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(base, "k8s-go", "credentials.yaml"), nil
+}
+
+// Get implements CredentialCache.
+func (c *FileCredentialCache) Get(key string) (*CachedCredential, bool, error) {
+	var (
+		result *CachedCredential
+		found  bool
+	)
+	err := c.withFileLock(false, func() error {
+		entries, err := c.readLocked()
+		if err != nil {
+			return err
+		}
+		entry, ok := entries[key]
+		if !ok {
+			return nil
+		}
+		if !entry.Expiry.IsZero() && !entry.Expiry.After(c.clock().Add(expirySkewPreventionDelay)) {
+			// Stale: treat exactly like a miss so the caller refreshes it.
+			return nil
+		}
+		result = &entry
+		found = true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return result, found, nil
+}
+
+// Set implements CredentialCache, writing atomically via a temp file plus rename so a
+// reader never observes a partially-written cache file.
+func (c *FileCredentialCache) Set(key string, cred *CachedCredential) error {
+	return c.withFileLock(true, func() error {
+		entries, err := c.readLocked()
+		if err != nil {
+			return err
+		}
+		entries[key] = *cred
+
+		b, err := yaml.Marshal(entries)
+		if err != nil {
+			return fmt.Errorf("failed to marshal credential cache: %v", err)
+		}
+
+		dir := filepath.Dir(c.path)
+		tmp, err := ioutil.TempFile(dir, ".credentials-*.yaml.tmp")
+		if err != nil {
+			return fmt.Errorf("failed to create credential cache temp file: %v", err)
+		}
+		tmpPath := tmp.Name()
+		defer os.Remove(tmpPath) // no-op once the rename below succeeds
+
+		if _, err := tmp.Write(b); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to write credential cache temp file: %v", err)
+		}
+		if err := tmp.Close(); err != nil {
+			return fmt.Errorf("failed to close credential cache temp file: %v", err)
+		}
+		if err := os.Chmod(tmpPath, 0600); err != nil {
+			return fmt.Errorf("failed to set credential cache file mode: %v", err)
+		}
+		if err := os.Rename(tmpPath, c.path); err != nil {
+			return fmt.Errorf("failed to persist credential cache: %v", err)
+		}
+		return nil
+	})
+}
+
+// readLocked reads and parses the cache file. Callers must hold the file lock.
+func (c *FileCredentialCache) readLocked() (map[string]CachedCredential, error) {
+	b, err := ioutil.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return map[string]CachedCredential{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential cache %q: %v", c.path, err)
+	}
+
+	entries := map[string]CachedCredential{}
+	if len(b) > 0 {
+		if err := yaml.Unmarshal(b, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse credential cache %q: %v", c.path, err)
+		}
+	}
+	if entries == nil {
+		entries = map[string]CachedCredential{}
+	}
+	return entries, nil
+}
+
+// withFileLock serializes access to the cache file, both within this process (via mu)
+// and across processes (via flock on a sibling lock file), then runs fn.
+func (c *FileCredentialCache) withFileLock(exclusive bool, fn func() error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return fmt.Errorf("failed to create credential cache directory: %v", err)
+	}
+
+	lockFile, err := os.OpenFile(c.path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open credential cache lock file: %v", err)
+	}
+	defer lockFile.Close()
+
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	if err := syscall.Flock(int(lockFile.Fd()), how); err != nil {
+		return fmt.Errorf("failed to lock credential cache: %v", err)
+	}
+	defer syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+
+	return fn()
+}
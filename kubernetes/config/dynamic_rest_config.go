@@ -0,0 +1,118 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultFileWatchInterval is how often a fileWatcher re-stats its file when
+// WithFileWatchInterval isn't used.
+const defaultFileWatchInterval = time.Minute
+
+// WithDynamicRestConfig makes the loader watch file-based credential material instead of
+// reading it once: the cluster's CertificateAuthority, the AuthInfo's
+// ClientCertificate/ClientKey, and its TokenFile. Use this for long-lived clients whose
+// credentials are rotated on disk, e.g. by cert-manager or a kubelet-style rotated
+// certificate, or a projected service account token. Inline *Data fields are unaffected,
+// since there's no file to watch.
+func WithDynamicRestConfig() KubeConfigLoaderOption {
+	return func(l *KubeConfigLoader) {
+		l.dynamicRestConfig = true
+	}
+}
+
+// WithFileWatchInterval overrides how often a DynamicRestConfig file watcher re-stats its
+// file. Defaults to defaultFileWatchInterval.
+func WithFileWatchInterval(d time.Duration) KubeConfigLoaderOption {
+	return func(l *KubeConfigLoader) {
+		l.fileWatchInterval = d
+	}
+}
+
+// watchInterval returns the interval a DynamicRestConfig file watcher re-stats its file
+// at.
+func (l *KubeConfigLoader) watchInterval() time.Duration {
+	if l.fileWatchInterval > 0 {
+		return l.fileWatchInterval
+	}
+	return defaultFileWatchInterval
+}
+
+// fileWatcher lazily reloads a file's contents for DynamicRestConfig mode. It re-reads
+// the file only when its mtime or size has changed since the last check, and no more
+// often than once per interval. A transient stat or read error falls back to the
+// last-good contents it has, if any, logging the error rather than failing the caller.
+type fileWatcher struct {
+	path     string
+	interval time.Duration
+
+	mu          sync.Mutex
+	lastChecked time.Time
+	lastModTime time.Time
+	lastSize    int64
+	lastContent []byte
+}
+
+// newFileWatcher returns a fileWatcher for path, re-stating it at most once per
+// interval.
+func newFileWatcher(path string, interval time.Duration) *fileWatcher {
+	return &fileWatcher{path: path, interval: interval}
+}
+
+// contents returns the file's current bytes.
+func (w *fileWatcher) contents() ([]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	if !w.lastChecked.IsZero() && now.Sub(w.lastChecked) < w.interval {
+		return w.lastContent, nil
+	}
+	w.lastChecked = now
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		if w.lastContent != nil {
+			log.Printf("k8s-go: failed to stat %q, keeping last-known contents: %v", w.path, err)
+			return w.lastContent, nil
+		}
+		return nil, err
+	}
+
+	if w.lastContent != nil && info.ModTime().Equal(w.lastModTime) && info.Size() == w.lastSize {
+		return w.lastContent, nil
+	}
+
+	b, err := ioutil.ReadFile(w.path)
+	if err != nil {
+		if w.lastContent != nil {
+			log.Printf("k8s-go: failed to read %q, keeping last-known contents: %v", w.path, err)
+			return w.lastContent, nil
+		}
+		return nil, err
+	}
+
+	w.lastModTime = info.ModTime()
+	w.lastSize = info.Size()
+	w.lastContent = b
+	return b, nil
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"k8s.io/go/kubernetes/config/api"
+)
+
+const (
+	// execCredentialAPIVersionV1Beta1 is the default ExecCredential APIVersion requested
+	// from a plugin when the kubeconfig doesn't specify one.
+	execCredentialAPIVersionV1Beta1 = "client.authentication.k8s.io/v1beta1"
+	// execCredentialAPIVersionV1 is the GA ExecCredential APIVersion.
+	execCredentialAPIVersionV1 = "client.authentication.k8s.io/v1"
+)
+
+// ExecCredential mirrors the client.authentication.k8s.io wire format exchanged with an
+// exec credential plugin: we send one with an empty Status on stdin, and expect one with
+// Status populated back on stdout.
+type ExecCredential struct {
+	Kind       string                `json:"kind"`
+	APIVersion string                `json:"apiVersion"`
+	Spec       ExecCredentialSpec    `json:"spec"`
+	Status     *ExecCredentialStatus `json:"status,omitempty"`
+}
+
+// ExecCredentialSpec carries cluster information to the plugin when requested via
+// api.ExecConfig.ProvideClusterInfo. It is intentionally empty here; KubeConfigLoader
+// does not yet pass cluster info to plugins.
+type ExecCredentialSpec struct{}
+
+// ExecCredentialStatus is the credential data returned by a plugin.
+type ExecCredentialStatus struct {
+	ExpirationTimestamp   *time.Time `json:"expirationTimestamp,omitempty"`
+	Token                 string     `json:"token,omitempty"`
+	ClientCertificateData string     `json:"clientCertificateData,omitempty"`
+	ClientKeyData         string     `json:"clientKeyData,omitempty"`
+}
+
+// ExecCredentialLoader invokes an `exec`-style credential plugin, analogous to
+// GoogleCredentialLoader for the built-in gcp provider.
+type ExecCredentialLoader interface {
+	GetExecCredential(cfg *api.ExecConfig) (*ExecCredential, error)
+}
+
+// DefaultExecCredentialLoader forks the plugin named in api.ExecConfig.Command, writes an
+// ExecCredential request to its stdin, and parses the ExecCredential it writes to stdout.
+type DefaultExecCredentialLoader struct{}
+
+// GetExecCredential implements ExecCredentialLoader.
+func (l DefaultExecCredentialLoader) GetExecCredential(cfg *api.ExecConfig) (*ExecCredential, error) {
+	apiVersion := cfg.APIVersion
+	if apiVersion == "" {
+		apiVersion = execCredentialAPIVersionV1Beta1
+	}
+
+	req, err := json.Marshal(ExecCredential{
+		Kind:       "ExecCredential",
+		APIVersion: apiVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Env = os.Environ()
+	for _, e := range cfg.Env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", e.Name, e.Value))
+	}
+	cmd.Stdin = bytes.NewReader(req)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if cfg.InstallHint != "" {
+			return nil, fmt.Errorf("exec plugin %q failed: %v (%s): %s", cfg.Command, err, cfg.InstallHint, stderr.String())
+		}
+		return nil, fmt.Errorf("exec plugin %q failed: %v: %s", cfg.Command, err, stderr.String())
+	}
+
+	var cred ExecCredential
+	if err := json.Unmarshal(stdout.Bytes(), &cred); err != nil {
+		return nil, fmt.Errorf("failed to parse ExecCredential from plugin %q: %v", cfg.Command, err)
+	}
+	if cred.Status == nil {
+		return nil, fmt.Errorf("exec plugin %q returned no status", cfg.Command)
+	}
+	return &cred, nil
+}
+
+// execCredentialExpired reports whether cred needs to be re-obtained: it has no
+// expiration (assume it's a long-lived token, not expired) or its expiration is within
+// expirySkewPreventionDelay of now.
+func execCredentialExpired(cred *ExecCredential) bool {
+	if cred == nil || cred.Status == nil || cred.Status.ExpirationTimestamp == nil {
+		return false
+	}
+	return !cred.Status.ExpirationTimestamp.After(time.Now().UTC().Add(expirySkewPreventionDelay))
+}
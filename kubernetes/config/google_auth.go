@@ -0,0 +1,48 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// cloudPlatformScope is the OAuth2 scope requested when minting new gcp auth-provider
+// access tokens via Application Default Credentials.
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// GoogleCredentialLoader mints a fresh access token for the `gcp` AuthProvider once its
+// cached one has expired.
+type GoogleCredentialLoader interface {
+	GetGoogleCredentials() (*oauth2.Token, error)
+}
+
+// DefaultGoogleCredentialLoader refreshes gcp auth-provider tokens using Application
+// Default Credentials (the same credentials `gcloud auth application-default login`
+// configures).
+type DefaultGoogleCredentialLoader struct{}
+
+// GetGoogleCredentials implements GoogleCredentialLoader.
+func (l DefaultGoogleCredentialLoader) GetGoogleCredentials() (*oauth2.Token, error) {
+	ts, err := google.DefaultTokenSource(context.Background(), cloudPlatformScope)
+	if err != nil {
+		return nil, err
+	}
+	return ts.Token()
+}
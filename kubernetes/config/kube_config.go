@@ -0,0 +1,501 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config loads kubeconfig files and resolves them into a RestConfig usable by
+// the generated client.
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	"k8s.io/go/kubernetes/config/api"
+)
+
+// expirySkewPreventionDelay is subtracted from the "now" used when comparing against a
+// credential's expiry, so that a credential due to expire imminently is treated as
+// already expired and refreshed early rather than handed out and immediately rejected.
+const expirySkewPreventionDelay = time.Minute
+
+// authProviderTimeLayout is the layout AuthProviderConfig.Config["expiry"] is encoded
+// with by the gcp auth provider.
+const authProviderTimeLayout = "2006-01-02 15:04:05"
+
+// bearerFormat is the Authorization header format used for token-based auth.
+const bearerFormat = "Bearer %s"
+
+const googleAuthProviderName = "gcp"
+
+// execAuthProviderName namespaces exec plugin cache entries (exec plugins aren't
+// AuthProviders, but they share the same CredentialCache as gcp/oidc).
+const execAuthProviderName = "exec"
+
+// KubeConfigLoader loads a kubeconfig file and resolves its active context into a
+// RestConfig, handling the static-token, basic-auth, gcp AuthProvider, and exec
+// credential plugin paths.
+type KubeConfigLoader struct {
+	rawConfig         api.Config
+	kubeConfigPath    string
+	activeContext     string
+	skipConfigPersist bool
+
+	gcLoader   GoogleCredentialLoader
+	execLoader ExecCredentialLoader
+	oidcLoader OIDCLoader
+
+	// cachedExecCredentials holds the last ExecCredential returned by the exec plugin for
+	// each AuthInfo/command (keyed the same way as credentialCache, via
+	// credentialCacheKey), so repeated loads don't re-invoke the plugin (which can be slow
+	// or interactive) until it expires. It's keyed rather than a single field so that
+	// switching the active context with SetActiveContext can't reuse one AuthInfo's
+	// in-memory credential for another.
+	cachedExecCredentials map[string]*ExecCredential
+
+	// strictTLS, set via WithStrictTLS, makes loadClusterInfo refuse clusters configured
+	// with InsecureSkipTLSVerify.
+	strictTLS bool
+	tlsConfig TLSConfig
+
+	// credentialCache, set via WithCredentialCache, is consulted before invoking any
+	// AuthProvider or exec plugin, so a still-valid credential from a previous process
+	// doesn't trigger another (possibly interactive) round trip.
+	credentialCache CredentialCache
+
+	// dynamicRestConfig, set via WithDynamicRestConfig, makes loadClusterInfo and
+	// loadAuthentication watch file-based certificate authority, client certificate/key,
+	// and token material instead of reading it once, so files rotated on disk after load
+	// time take effect without calling SetActiveContext again.
+	dynamicRestConfig bool
+	// fileWatchInterval, set via WithFileWatchInterval, overrides how often a
+	// DynamicRestConfig file watcher re-stats its file.
+	fileWatchInterval time.Duration
+
+	restConfig RestConfig
+}
+
+// KubeConfigLoaderOption configures optional behavior on a KubeConfigLoader.
+type KubeConfigLoaderOption func(*KubeConfigLoader)
+
+// WithCredentialCache makes the loader consult cache before invoking the gcp, oidc, or
+// exec providers, and write refreshed credentials back to it.
+func WithCredentialCache(cache CredentialCache) KubeConfigLoaderOption {
+	return func(l *KubeConfigLoader) {
+		l.credentialCache = cache
+	}
+}
+
+// credentialCacheLookup returns the cached credential for key, or ok=false if no cache
+// is configured, there's no entry, or the entry is stale.
+func (l *KubeConfigLoader) credentialCacheLookup(key string) (cred *CachedCredential, ok bool) {
+	if l.credentialCache == nil {
+		return nil, false
+	}
+	cred, ok, err := l.credentialCache.Get(key)
+	if err != nil {
+		return nil, false
+	}
+	return cred, ok
+}
+
+// credentialCacheStore writes cred under key. Failures are ignored: the loader already
+// has a usable credential in memory, and a cache write failure shouldn't fail the load.
+func (l *KubeConfigLoader) credentialCacheStore(key string, cred *CachedCredential) {
+	if l.credentialCache == nil {
+		return
+	}
+	_ = l.credentialCache.Set(key, cred)
+}
+
+// NewKubeConfigLoader reads and parses the kubeconfig file at path.
+func NewKubeConfigLoader(path string, opts ...KubeConfigLoaderOption) (*KubeConfigLoader, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig %q: %v", path, err)
+	}
+
+	var rawConfig api.Config
+	if err := yaml.Unmarshal(b, &rawConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig %q: %v", path, err)
+	}
+
+	l := &KubeConfigLoader{
+		rawConfig:      rawConfig,
+		kubeConfigPath: path,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l, nil
+}
+
+// RestConfig returns the RestConfig resolved by the most recent SetActiveContext,
+// loadAuthentication, and loadClusterInfo calls.
+func (l *KubeConfigLoader) RestConfig() RestConfig {
+	return l.restConfig
+}
+
+// SetActiveContext selects, by name, the context that subsequent loadAuthentication and
+// loadClusterInfo calls resolve.
+func (l *KubeConfigLoader) SetActiveContext(name string) error {
+	if _, err := l.findContext(name); err != nil {
+		return err
+	}
+	l.activeContext = name
+	return nil
+}
+
+func (l *KubeConfigLoader) findContext(name string) (*api.Context, error) {
+	for i := range l.rawConfig.Contexts {
+		if l.rawConfig.Contexts[i].Name == name {
+			return &l.rawConfig.Contexts[i].Context, nil
+		}
+	}
+	return nil, fmt.Errorf("context %q does not exist in kubeconfig", name)
+}
+
+func (l *KubeConfigLoader) currentContext() (*api.Context, error) {
+	return l.findContext(l.activeContext)
+}
+
+func (l *KubeConfigLoader) currentCluster() (*api.Cluster, error) {
+	ctx, err := l.currentContext()
+	if err != nil {
+		return nil, err
+	}
+	for i := range l.rawConfig.Clusters {
+		if l.rawConfig.Clusters[i].Name == ctx.Cluster {
+			return &l.rawConfig.Clusters[i].Cluster, nil
+		}
+	}
+	return nil, fmt.Errorf("cluster %q does not exist in kubeconfig", ctx.Cluster)
+}
+
+// currentAuthInfo returns the active context's AuthInfo, or nil, nil if the context
+// doesn't reference one or references one that isn't defined.
+func (l *KubeConfigLoader) currentAuthInfo() (*api.AuthInfo, error) {
+	ctx, err := l.currentContext()
+	if err != nil {
+		return nil, err
+	}
+	for i := range l.rawConfig.AuthInfos {
+		if l.rawConfig.AuthInfos[i].Name == ctx.AuthInfo {
+			return &l.rawConfig.AuthInfos[i].AuthInfo, nil
+		}
+	}
+	return nil, nil
+}
+
+// loadClusterInfo resolves the active context's cluster and client TLS material into
+// restConfig.
+func (l *KubeConfigLoader) loadClusterInfo() error {
+	cluster, err := l.currentCluster()
+	if err != nil {
+		return err
+	}
+
+	if l.strictTLS && cluster.InsecureSkipTLSVerify {
+		return fmt.Errorf("strict TLS mode: refusing to load cluster %q configured with insecure-skip-tls-verify", cluster.Server)
+	}
+
+	u, err := url.Parse(cluster.Server)
+	if err != nil {
+		return fmt.Errorf("failed to parse cluster server %q: %v", cluster.Server, err)
+	}
+	l.restConfig.basePath = strings.TrimRight(cluster.Server, "/")
+	l.restConfig.host = u.Host
+	l.restConfig.scheme = u.Scheme
+	l.restConfig.skipTLSVerify = cluster.InsecureSkipTLSVerify
+
+	caCert, err := dataOrFile(cluster.CertificateAuthorityData, cluster.CertificateAuthority)
+	if err != nil {
+		return err
+	}
+	l.restConfig.caCert = caCert
+	if l.dynamicRestConfig && len(cluster.CertificateAuthorityData) == 0 && cluster.CertificateAuthority != "" {
+		l.restConfig.caCertWatcher = newFileWatcher(cluster.CertificateAuthority, l.watchInterval())
+	}
+
+	authInfo, err := l.currentAuthInfo()
+	if err != nil {
+		return err
+	}
+	if authInfo == nil {
+		return nil
+	}
+
+	// Only set the client cert/key from the AuthInfo's own fields when it declares them;
+	// leave any value already set by an exec credential plugin in loadAuthentication alone.
+	if len(authInfo.ClientCertificateData) > 0 || authInfo.ClientCertificate != "" {
+		clientCert, err := dataOrFile(authInfo.ClientCertificateData, authInfo.ClientCertificate)
+		if err != nil {
+			return err
+		}
+		l.restConfig.clientCert = clientCert
+		if l.dynamicRestConfig && len(authInfo.ClientCertificateData) == 0 && authInfo.ClientCertificate != "" {
+			l.restConfig.clientCertWatcher = newFileWatcher(authInfo.ClientCertificate, l.watchInterval())
+		}
+	}
+
+	if len(authInfo.ClientKeyData) > 0 || authInfo.ClientKey != "" {
+		clientKey, err := dataOrFile(authInfo.ClientKeyData, authInfo.ClientKey)
+		if err != nil {
+			return err
+		}
+		l.restConfig.clientKey = clientKey
+		if l.dynamicRestConfig && len(authInfo.ClientKeyData) == 0 && authInfo.ClientKey != "" {
+			l.restConfig.clientKeyWatcher = newFileWatcher(authInfo.ClientKey, l.watchInterval())
+		}
+	}
+
+	return nil
+}
+
+// loadAuthentication resolves the active context's AuthInfo into an Authorization header
+// value (or client certificate, for exec plugins that return one) on restConfig.
+func (l *KubeConfigLoader) loadAuthentication() error {
+	authInfo, err := l.currentAuthInfo()
+	if err != nil {
+		return err
+	}
+	if authInfo == nil {
+		return nil
+	}
+
+	if authInfo.Exec != nil {
+		return l.loadExecAuth(authInfo.Exec)
+	}
+
+	if authInfo.AuthProvider != nil {
+		return l.loadAuthProvider(authInfo.AuthProvider)
+	}
+
+	if authInfo.Token != "" {
+		l.restConfig.token = fmt.Sprintf(bearerFormat, authInfo.Token)
+		return nil
+	}
+
+	if authInfo.TokenFile != "" {
+		b, err := ioutil.ReadFile(authInfo.TokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to get data or file: %v", err)
+		}
+		l.restConfig.token = fmt.Sprintf(bearerFormat, strings.TrimSpace(string(b)))
+		if l.dynamicRestConfig {
+			watcher := newFileWatcher(authInfo.TokenFile, l.watchInterval())
+			l.restConfig.bearerTokenProvider = func() (string, error) {
+				b, err := watcher.contents()
+				if err != nil {
+					return "", fmt.Errorf("failed to get data or file: %v", err)
+				}
+				return fmt.Sprintf(bearerFormat, strings.TrimSpace(string(b))), nil
+			}
+		}
+		return nil
+	}
+
+	if authInfo.Username != "" {
+		basic := base64.StdEncoding.EncodeToString([]byte(authInfo.Username + ":" + authInfo.Password))
+		l.restConfig.token = "Basic " + basic
+		return nil
+	}
+
+	return nil
+}
+
+// AuthProviderLoader resolves a named kubeconfig AuthProvider (e.g. "gcp", "oidc") into
+// a bearer token on a KubeConfigLoader's RestConfig, refreshing it first if it has
+// expired. New providers are added by registering an implementation in
+// authProviderLoaders under their AuthProviderConfig.Name.
+type AuthProviderLoader interface {
+	LoadAuthProvider(l *KubeConfigLoader, provider *api.AuthProviderConfig) error
+}
+
+// authProviderLoaders maps an AuthProviderConfig.Name to the loader that handles it.
+var authProviderLoaders = map[string]AuthProviderLoader{
+	googleAuthProviderName: googleAuthProviderLoader{},
+	oidcAuthProviderName:   oidcAuthProviderLoader{},
+}
+
+// loadAuthProvider dispatches to the loader registered for provider.Name.
+func (l *KubeConfigLoader) loadAuthProvider(provider *api.AuthProviderConfig) error {
+	loader, ok := authProviderLoaders[provider.Name]
+	if !ok {
+		return fmt.Errorf("unsupported auth provider %q", provider.Name)
+	}
+	return loader.LoadAuthProvider(l, provider)
+}
+
+// googleAuthProviderLoader registers the `gcp` AuthProvider under the AuthProviderLoader
+// interface.
+type googleAuthProviderLoader struct{}
+
+// LoadAuthProvider implements AuthProviderLoader.
+func (googleAuthProviderLoader) LoadAuthProvider(l *KubeConfigLoader, provider *api.AuthProviderConfig) error {
+	return l.loadGoogleAuth(provider)
+}
+
+// loadGoogleAuth implements the `gcp` AuthProvider: reuse the cached access-token from
+// the kubeconfig unless it's expired, in which case ask gcLoader to mint a new one and
+// update the cached config.
+func (l *KubeConfigLoader) loadGoogleAuth(provider *api.AuthProviderConfig) error {
+	accessToken := provider.Config["access-token"]
+	expired := googleAuthExpired(provider.Config["expiry"])
+
+	ctx, err := l.currentContext()
+	if err != nil {
+		return err
+	}
+	cacheKey := credentialCacheKey(ctx.AuthInfo, googleAuthProviderName, "")
+
+	if expired {
+		if cached, ok := l.credentialCacheLookup(cacheKey); ok {
+			accessToken = cached.Token
+			expired = false
+		}
+	}
+
+	if expired {
+		gcLoader := l.gcLoader
+		if gcLoader == nil {
+			gcLoader = DefaultGoogleCredentialLoader{}
+		}
+		tok, err := gcLoader.GetGoogleCredentials()
+		if err != nil {
+			return fmt.Errorf("failed to refresh gcp credentials: %v", err)
+		}
+		accessToken = tok.AccessToken
+		l.credentialCacheStore(cacheKey, &CachedCredential{Token: accessToken, Expiry: tok.Expiry.UTC()})
+
+		if provider.Config == nil {
+			provider.Config = map[string]string{}
+		}
+		provider.Config["access-token"] = accessToken
+		provider.Config["expiry"] = tok.Expiry.UTC().Format(authProviderTimeLayout)
+
+		if !l.skipConfigPersist {
+			authInfo, err := l.currentAuthInfo()
+			if err != nil {
+				return err
+			}
+			if err := setUserWithName(l.rawConfig.AuthInfos, ctx.AuthInfo, authInfo); err != nil {
+				return err
+			}
+			if err := l.persist(); err != nil {
+				return err
+			}
+		}
+	}
+
+	l.restConfig.token = fmt.Sprintf(bearerFormat, accessToken)
+	return nil
+}
+
+func googleAuthExpired(expiry string) bool {
+	if expiry == "" {
+		return true
+	}
+	t, err := time.Parse(authProviderTimeLayout, expiry)
+	if err != nil {
+		return true
+	}
+	return !t.After(time.Now().UTC().Add(expirySkewPreventionDelay))
+}
+
+// loadExecAuth implements the `exec` credential plugin contract: reuse the cached
+// ExecCredential unless it's expired, in which case invoke the plugin for a new one.
+func (l *KubeConfigLoader) loadExecAuth(cfg *api.ExecConfig) error {
+	ctx, err := l.currentContext()
+	if err != nil {
+		return err
+	}
+	cacheKey := credentialCacheKey(ctx.AuthInfo, execAuthProviderName, cfg.Command)
+
+	cred := l.cachedExecCredentials[cacheKey]
+	if execCredentialExpired(cred) {
+		cred = nil
+	}
+
+	if cred == nil {
+		if cached, ok := l.credentialCacheLookup(cacheKey); ok {
+			cred = &ExecCredential{Status: &ExecCredentialStatus{
+				Token:                 cached.Token,
+				ClientCertificateData: cached.ClientCert,
+				ClientKeyData:         cached.ClientKey,
+			}}
+			if !cached.Expiry.IsZero() {
+				cred.Status.ExpirationTimestamp = &cached.Expiry
+			}
+		}
+	}
+
+	if cred == nil {
+		execLoader := l.execLoader
+		if execLoader == nil {
+			execLoader = DefaultExecCredentialLoader{}
+		}
+		var err error
+		cred, err = execLoader.GetExecCredential(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to get exec credential: %v", err)
+		}
+		if l.cachedExecCredentials == nil {
+			l.cachedExecCredentials = map[string]*ExecCredential{}
+		}
+		l.cachedExecCredentials[cacheKey] = cred
+
+		cacheEntry := &CachedCredential{
+			Token:      cred.Status.Token,
+			ClientCert: cred.Status.ClientCertificateData,
+			ClientKey:  cred.Status.ClientKeyData,
+		}
+		if cred.Status.ExpirationTimestamp != nil {
+			cacheEntry.Expiry = cred.Status.ExpirationTimestamp.UTC()
+		}
+		l.credentialCacheStore(cacheKey, cacheEntry)
+	}
+
+	switch {
+	case cred.Status.Token != "":
+		l.restConfig.token = fmt.Sprintf(bearerFormat, cred.Status.Token)
+	case cred.Status.ClientCertificateData != "" && cred.Status.ClientKeyData != "":
+		l.restConfig.clientCert = []byte(cred.Status.ClientCertificateData)
+		l.restConfig.clientKey = []byte(cred.Status.ClientKeyData)
+	default:
+		return fmt.Errorf("exec plugin %q returned neither a token nor a client certificate", cfg.Command)
+	}
+
+	return nil
+}
+
+// persist writes the (possibly just-refreshed) raw kubeconfig back to kubeConfigPath.
+func (l *KubeConfigLoader) persist() error {
+	if l.kubeConfigPath == "" {
+		return nil
+	}
+	b, err := yaml.Marshal(l.rawConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal kubeconfig: %v", err)
+	}
+	if err := ioutil.WriteFile(l.kubeConfigPath, b, 0600); err != nil {
+		return fmt.Errorf("failed to persist kubeconfig %q: %v", l.kubeConfigPath, err)
+	}
+	return nil
+}
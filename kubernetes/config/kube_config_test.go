@@ -17,12 +17,16 @@ limitations under the License.
 package config
 
 import (
+	"crypto/tls"
 	b64 "encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/url"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -65,8 +69,30 @@ var (
 
 	// test time set to time.Now() + 2 * expirySkewPreventionDelay, which doesn't expire
 	testTokenNoExpiry = time.Now().Add(2 * expirySkewPreventionDelay).UTC().Format("2006-01-02 15:04:05")
+
+	// testExecExpiry is in the past, used to seed an already-expired cached ExecCredential.
+	testExecExpiry = time.Now().UTC().Add(-time.Hour)
+	// testExecNoExpiry is far enough in the future to never be treated as expired.
+	testExecNoExpiry = time.Now().UTC().Add(2 * expirySkewPreventionDelay)
+
+	// testIDTokenNoExpiry is a JWT whose "exp" claim doesn't expire.
+	testIDTokenNoExpiry = makeTestIDToken(time.Now().Add(2 * expirySkewPreventionDelay))
+	// testIDTokenExpired is a JWT whose "exp" claim is always in the past.
+	testIDTokenExpired = makeTestIDToken(time.Now().Add(-time.Hour))
 )
 
+// testRefreshedIDToken is what FakeOIDCLoader returns in place of an expired id-token.
+const testRefreshedIDToken = "refreshed-id-token"
+
+// makeTestIDToken builds a JWT with the given expiry as its only claim; its signature
+// is never verified by the loader, so it's left empty.
+func makeTestIDToken(exp time.Time) string {
+	payload, _ := json.Marshal(struct {
+		Exp int64 `json:"exp"`
+	}{exp.Unix()})
+	return "header." + b64.RawURLEncoding.EncodeToString(payload) + ".sig"
+}
+
 var testKubeConfig = api.Config{
 	CurrentContext: "no_user",
 	Contexts: []api.NamedContext{
@@ -139,6 +165,41 @@ var testKubeConfig = api.Config{
 				AuthInfo: "ssl_local_file",
 			},
 		},
+		{
+			Name: "exec_token",
+			Context: api.Context{
+				Cluster:  "default",
+				AuthInfo: "exec_token",
+			},
+		},
+		{
+			Name: "exec_cert",
+			Context: api.Context{
+				Cluster:  "default",
+				AuthInfo: "exec_cert",
+			},
+		},
+		{
+			Name: "exec_expired",
+			Context: api.Context{
+				Cluster:  "default",
+				AuthInfo: "exec_expired",
+			},
+		},
+		{
+			Name: "oidc",
+			Context: api.Context{
+				Cluster:  "default",
+				AuthInfo: "oidc",
+			},
+		},
+		{
+			Name: "expired_oidc",
+			Context: api.Context{
+				Cluster:  "default",
+				AuthInfo: "expired_oidc",
+			},
+		},
 	},
 	Clusters: []api.NamedCluster{
 		{
@@ -150,7 +211,7 @@ var testKubeConfig = api.Config{
 		{
 			Name: "ssl",
 			Cluster: api.Cluster{
-				Server: testSSLServer,
+				Server:                   testSSLServer,
 				CertificateAuthorityData: testCertAuthBase64,
 			},
 		},
@@ -225,7 +286,7 @@ var testKubeConfig = api.Config{
 		{
 			Name: "ssl",
 			AuthInfo: api.AuthInfo{
-				Token: testDataBase64,
+				Token:                 testDataBase64,
 				ClientCertificateData: testClientCertBase64,
 				ClientKeyData:         testClientKeyBase64,
 			},
@@ -246,6 +307,54 @@ var testKubeConfig = api.Config{
 				ClientKey:         "/tmp/client-ssl-test-client-key-local-file",
 			},
 		},
+		{
+			Name: "exec_token",
+			AuthInfo: api.AuthInfo{
+				Exec: &api.ExecConfig{Command: "test-exec-plugin", APIVersion: execCredentialAPIVersionV1Beta1},
+			},
+		},
+		{
+			Name: "exec_cert",
+			AuthInfo: api.AuthInfo{
+				Exec: &api.ExecConfig{Command: "test-exec-plugin", APIVersion: execCredentialAPIVersionV1Beta1},
+			},
+		},
+		{
+			Name: "exec_expired",
+			AuthInfo: api.AuthInfo{
+				Exec: &api.ExecConfig{Command: "test-exec-plugin", APIVersion: execCredentialAPIVersionV1Beta1},
+			},
+		},
+		{
+			Name: "oidc",
+			AuthInfo: api.AuthInfo{
+				AuthProvider: &api.AuthProviderConfig{
+					Name: "oidc",
+					Config: map[string]string{
+						"idp-issuer-url": "https://issuer.example.com",
+						"client-id":      "test-client-id",
+						"client-secret":  "test-client-secret",
+						"id-token":       testIDTokenNoExpiry,
+						"refresh-token":  "test-refresh-token",
+					},
+				},
+			},
+		},
+		{
+			Name: "expired_oidc",
+			AuthInfo: api.AuthInfo{
+				AuthProvider: &api.AuthProviderConfig{
+					Name: "oidc",
+					Config: map[string]string{
+						"idp-issuer-url": "https://issuer.example.com",
+						"client-id":      "test-client-id",
+						"client-secret":  "test-client-secret",
+						"id-token":       testIDTokenExpired,
+						"refresh-token":  "test-refresh-token",
+					},
+				},
+			},
+		},
 	},
 }
 
@@ -260,6 +369,9 @@ func TestLoadKubeConfig(t *testing.T) {
 		Key           []byte
 		SkipTLSVerify bool
 		GCLoader      GoogleCredentialLoader
+		ExecLoader    ExecCredentialLoader
+		CachedExec    *ExecCredential
+		OIDCLoader    OIDCLoader
 	}{
 		{
 			ActiveContext: "no_user",
@@ -307,6 +419,43 @@ func TestLoadKubeConfig(t *testing.T) {
 			Key:           testClientKeyBase64,
 			SkipTLSVerify: true,
 		},
+		{
+			ActiveContext: "exec_token",
+			Server:        testServer,
+			Token:         fmt.Sprintf(bearerTokenFormat, testDataBase64),
+			ExecLoader:    FakeExecCredentialLoader{},
+		},
+		{
+			ActiveContext: "exec_cert",
+			Server:        testServer,
+			Cert:          testClientCertBase64,
+			Key:           testClientKeyBase64,
+			ExecLoader:    FakeExecCredentialLoaderCert{},
+		},
+		{
+			ActiveContext: "exec_expired",
+			Server:        testServer,
+			Token:         fmt.Sprintf(bearerTokenFormat, testAnotherDataBase64),
+			ExecLoader:    FakeExecCredentialLoaderExpired{},
+			CachedExec: &ExecCredential{
+				Status: &ExecCredentialStatus{
+					Token:               testDataBase64,
+					ExpirationTimestamp: &testExecExpiry,
+				},
+			},
+		},
+		{
+			ActiveContext: "oidc",
+			Server:        testServer,
+			Token:         fmt.Sprintf(bearerTokenFormat, testIDTokenNoExpiry),
+			OIDCLoader:    FakeOIDCLoaderNoRefresh{},
+		},
+		{
+			ActiveContext: "expired_oidc",
+			Server:        testServer,
+			Token:         fmt.Sprintf(bearerTokenFormat, testRefreshedIDToken),
+			OIDCLoader:    FakeOIDCLoader{},
+		},
 	}
 
 	for _, tc := range tcs {
@@ -319,6 +468,13 @@ func TestLoadKubeConfig(t *testing.T) {
 			rawConfig:         testKubeConfig,
 			skipConfigPersist: true,
 			gcLoader:          tc.GCLoader,
+			execLoader:        tc.ExecLoader,
+			oidcLoader:        tc.OIDCLoader,
+		}
+		if tc.CachedExec != nil {
+			// The AuthInfo name matches the context name for every exec_* fixture above.
+			cacheKey := credentialCacheKey(tc.ActiveContext, execAuthProviderName, "test-exec-plugin")
+			actual.cachedExecCredentials = map[string]*ExecCredential{cacheKey: tc.CachedExec}
 		}
 		err = actual.SetActiveContext(tc.ActiveContext)
 		if err != nil {
@@ -339,6 +495,44 @@ func TestLoadKubeConfig(t *testing.T) {
 	}
 }
 
+// TestLoadKubeConfigExecAuthSwitchContext reproduces a cache leak across SetActiveContext:
+// a loader that authenticates against one exec context and then switches to a different
+// exec context must invoke the second context's plugin rather than reusing the first
+// context's in-memory ExecCredential.
+func TestLoadKubeConfigExecAuthSwitchContext(t *testing.T) {
+	actual := KubeConfigLoader{
+		rawConfig:         testKubeConfig,
+		skipConfigPersist: true,
+		execLoader:        FakeExecCredentialLoader{},
+	}
+
+	if err := actual.SetActiveContext("exec_token"); err != nil {
+		t.Fatalf("unexpected error setting active context: %v", err)
+	}
+	if err := actual.loadAuthentication(); err != nil {
+		t.Fatalf("unexpected error loading authentication: %v", err)
+	}
+	want := fmt.Sprintf(bearerTokenFormat, testDataBase64)
+	if actual.restConfig.token != want {
+		t.Fatalf("got token %q, want %q", actual.restConfig.token, want)
+	}
+
+	actual.execLoader = FakeExecCredentialLoaderCert{}
+	if err := actual.SetActiveContext("exec_cert"); err != nil {
+		t.Fatalf("unexpected error setting active context: %v", err)
+	}
+	if err := actual.loadAuthentication(); err != nil {
+		t.Fatalf("unexpected error loading authentication: %v", err)
+	}
+
+	// exec_cert's plugin returns a client certificate, not a token; if the loader had
+	// wrongly reused exec_token's cached (token-mode) credential instead of invoking
+	// FakeExecCredentialLoaderCert, these would be empty.
+	if string(actual.restConfig.clientCert) != string(testClientCertBase64) || string(actual.restConfig.clientKey) != string(testClientKeyBase64) {
+		t.Errorf("got cert %q key %q, want the exec_cert plugin's client certificate", actual.restConfig.clientCert, actual.restConfig.clientKey)
+	}
+}
+
 func TestLoadKubeConfigSSLNoFile(t *testing.T) {
 	actual := KubeConfigLoader{
 		rawConfig:         testKubeConfig,
@@ -424,6 +618,94 @@ func TestLoadKubeConfigSSLLocalFile(t *testing.T) {
 	}
 }
 
+func TestLoadKubeConfigStrictTLSRefusesInsecure(t *testing.T) {
+	actual := KubeConfigLoader{
+		rawConfig:         testKubeConfig,
+		skipConfigPersist: true,
+	}
+	WithStrictTLS()(&actual)
+
+	if err := actual.SetActiveContext("ssl_no_verification"); err != nil {
+		t.Fatalf("unexpected error setting active context: %v", err)
+	}
+
+	actual.loadAuthentication()
+	err := actual.loadClusterInfo()
+	if err == nil || !strings.Contains(err.Error(), "insecure-skip-tls-verify") {
+		t.Errorf("expected strict TLS to refuse a cluster with insecure-skip-tls-verify, got: %v", err)
+	}
+}
+
+func TestLoadKubeConfigStrictTLSConfig(t *testing.T) {
+	actual := KubeConfigLoader{
+		rawConfig:         testKubeConfig,
+		skipConfigPersist: true,
+	}
+	WithStrictTLS()(&actual)
+
+	if err := actual.SetActiveContext("no_user"); err != nil {
+		t.Fatalf("unexpected error setting active context: %v", err)
+	}
+
+	actual.loadAuthentication()
+	if err := actual.loadClusterInfo(); err != nil {
+		t.Fatalf("unexpected error loading cluster info: %v", err)
+	}
+
+	tlsCfg, err := actual.TLSConfig()
+	if err != nil {
+		t.Fatalf("unexpected error building tls config: %v", err)
+	}
+	if tlsCfg.MinVersion != tls.VersionTLS12 {
+		t.Errorf("expected MinVersion TLS 1.2, got %v", tlsCfg.MinVersion)
+	}
+	if !reflect.DeepEqual(tlsCfg.CipherSuites, defaultStrictCipherSuites) {
+		t.Errorf("cipher suites mismatch: want %v, got %v", defaultStrictCipherSuites, tlsCfg.CipherSuites)
+	}
+	if tlsCfg.GetClientCertificate != nil {
+		t.Errorf("expected no GetClientCertificate for a context without a client certificate")
+	}
+}
+
+func TestLoadKubeConfigTLSConfigGetClientCertificate(t *testing.T) {
+	// A context whose cluster carries no CertificateAuthorityData, so building the
+	// tls.Config below doesn't also need to PEM-parse the package's fake CA test data.
+	rawConfig := testKubeConfig
+	rawConfig.AuthInfos = append(append([]api.NamedAuthInfo{}, testKubeConfig.AuthInfos...), api.NamedAuthInfo{
+		Name: "tls_client_cert",
+		AuthInfo: api.AuthInfo{
+			ClientCertificateData: testClientCertBase64,
+			ClientKeyData:         testClientKeyBase64,
+		},
+	})
+	rawConfig.Contexts = append(append([]api.NamedContext{}, testKubeConfig.Contexts...), api.NamedContext{
+		Name:    "tls_client_cert",
+		Context: api.Context{Cluster: "default", AuthInfo: "tls_client_cert"},
+	})
+
+	actual := KubeConfigLoader{
+		rawConfig:         rawConfig,
+		skipConfigPersist: true,
+	}
+
+	if err := actual.SetActiveContext("tls_client_cert"); err != nil {
+		t.Fatalf("unexpected error setting active context: %v", err)
+	}
+
+	actual.loadAuthentication()
+	if err := actual.loadClusterInfo(); err != nil {
+		t.Fatalf("unexpected error loading cluster info: %v", err)
+	}
+
+	tlsCfg, err := actual.TLSConfig()
+	if err != nil {
+		t.Fatalf("unexpected error building tls config: %v", err)
+	}
+	if tlsCfg.GetClientCertificate == nil {
+		t.Fatalf("expected GetClientCertificate to be set for a context with a client certificate")
+	}
+}
+
 func FakeConfig(server, token string, caCert, clientCert, clientKey []byte, skipTLSVerify bool) (RestConfig, error) {
 	u, err := url.Parse(server)
 	if err != nil {
@@ -456,4 +738,362 @@ type FakeGoogleCredentialLoaderNoRefresh struct{}
 
 func (l FakeGoogleCredentialLoaderNoRefresh) GetGoogleCredentials() (*oauth2.Token, error) {
 	return nil, fmt.Errorf("should not be called")
-}
\ No newline at end of file
+}
+
+// FakeExecCredentialLoader returns a token-mode ExecCredential.
+type FakeExecCredentialLoader struct{}
+
+func (l FakeExecCredentialLoader) GetExecCredential(cfg *api.ExecConfig) (*ExecCredential, error) {
+	return &ExecCredential{
+		Kind:       "ExecCredential",
+		APIVersion: execCredentialAPIVersionV1Beta1,
+		Status: &ExecCredentialStatus{
+			Token: testDataBase64,
+		},
+	}, nil
+}
+
+// FakeExecCredentialLoaderCert returns a client-certificate-mode ExecCredential.
+type FakeExecCredentialLoaderCert struct{}
+
+func (l FakeExecCredentialLoaderCert) GetExecCredential(cfg *api.ExecConfig) (*ExecCredential, error) {
+	return &ExecCredential{
+		Kind:       "ExecCredential",
+		APIVersion: execCredentialAPIVersionV1Beta1,
+		Status: &ExecCredentialStatus{
+			ClientCertificateData: string(testClientCertBase64),
+			ClientKeyData:         string(testClientKeyBase64),
+		},
+	}, nil
+}
+
+// FakeExecCredentialLoaderExpired returns a fresh, non-expired ExecCredential; it's used
+// together with a pre-seeded, already-expired cached one to exercise the re-exec path.
+type FakeExecCredentialLoaderExpired struct{}
+
+func (l FakeExecCredentialLoaderExpired) GetExecCredential(cfg *api.ExecConfig) (*ExecCredential, error) {
+	return &ExecCredential{
+		Kind:       "ExecCredential",
+		APIVersion: execCredentialAPIVersionV1Beta1,
+		Status: &ExecCredentialStatus{
+			Token:               testAnotherDataBase64,
+			ExpirationTimestamp: &testExecNoExpiry,
+		},
+	}, nil
+}
+
+type FakeOIDCLoader struct{}
+
+func (l FakeOIDCLoader) RefreshOIDCToken(provider *api.AuthProviderConfig) (string, string, error) {
+	return testRefreshedIDToken, "test-new-refresh-token", nil
+}
+
+type FakeOIDCLoaderNoRefresh struct{}
+
+func (l FakeOIDCLoaderNoRefresh) RefreshOIDCToken(provider *api.AuthProviderConfig) (string, string, error) {
+	return "", "", fmt.Errorf("should not be called")
+}
+
+func TestFileCredentialCacheGetSet(t *testing.T) {
+	now := time.Now().UTC()
+	cache, err := NewFileCredentialCache(
+		WithCredentialCachePath(filepath.Join(t.TempDir(), "credentials.yaml")),
+		WithCredentialCacheClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error constructing cache: %v", err)
+	}
+
+	if _, ok, err := cache.Get("missing"); err != nil || ok {
+		t.Errorf("expected miss for an absent key, got ok=%v err=%v", ok, err)
+	}
+
+	fresh := &CachedCredential{Token: testDataBase64, Expiry: now.Add(time.Hour)}
+	if err := cache.Set("fresh", fresh); err != nil {
+		t.Fatalf("unexpected error storing credential: %v", err)
+	}
+	got, ok, err := cache.Get("fresh")
+	if err != nil || !ok {
+		t.Fatalf("expected hit for a fresh entry, got ok=%v err=%v", ok, err)
+	}
+	if !reflect.DeepEqual(*got, *fresh) {
+		t.Errorf("got %+v, want %+v", *got, *fresh)
+	}
+
+	stale := &CachedCredential{Token: testAnotherDataBase64, Expiry: now.Add(-time.Hour)}
+	if err := cache.Set("stale", stale); err != nil {
+		t.Fatalf("unexpected error storing credential: %v", err)
+	}
+	if _, ok, err := cache.Get("stale"); err != nil || ok {
+		t.Errorf("expected miss for a stale entry, got ok=%v err=%v", ok, err)
+	}
+
+	// A second cache instance backed by the same file picks up what the first wrote.
+	cache2, err := NewFileCredentialCache(
+		WithCredentialCachePath(cache.path),
+		WithCredentialCacheClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error constructing second cache: %v", err)
+	}
+	if _, ok, err := cache2.Get("fresh"); err != nil || !ok {
+		t.Errorf("expected second cache to see the first's write, got ok=%v err=%v", ok, err)
+	}
+}
+
+// TestFileCredentialCacheConcurrentWriters exercises withFileLock's flock-based
+// serialization: many goroutines, each with its own FileCredentialCache instance backed
+// by the same file, set and get distinct keys concurrently. If locking were missing or
+// broken, concurrent writers racing on the temp-file-plus-rename in Set would corrupt the
+// shared yaml file and surface as a Get/Set error or a lost write below.
+func TestFileCredentialCacheConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.yaml")
+
+	const numWriters = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, numWriters)
+
+	for i := 0; i < numWriters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			cache, err := NewFileCredentialCache(WithCredentialCachePath(path))
+			if err != nil {
+				errs <- fmt.Errorf("writer %d: unexpected error constructing cache: %v", i, err)
+				return
+			}
+
+			key := fmt.Sprintf("key-%d", i)
+			cred := &CachedCredential{Token: fmt.Sprintf("token-%d", i)}
+			if err := cache.Set(key, cred); err != nil {
+				errs <- fmt.Errorf("writer %d: unexpected error storing credential: %v", i, err)
+				return
+			}
+
+			got, ok, err := cache.Get(key)
+			if err != nil {
+				errs <- fmt.Errorf("writer %d: unexpected error reading back credential: %v", i, err)
+				return
+			}
+			if !ok || got.Token != cred.Token {
+				errs <- fmt.Errorf("writer %d: got (%+v, %v), want (%+v, true)", i, got, ok, cred)
+				return
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// Every writer's key must have survived the concurrent Sets.
+	cache, err := NewFileCredentialCache(WithCredentialCachePath(path))
+	if err != nil {
+		t.Fatalf("unexpected error constructing cache: %v", err)
+	}
+	for i := 0; i < numWriters; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		got, ok, err := cache.Get(key)
+		if err != nil || !ok {
+			t.Errorf("key %q missing after concurrent writes: ok=%v err=%v", key, ok, err)
+			continue
+		}
+		want := fmt.Sprintf("token-%d", i)
+		if got.Token != want {
+			t.Errorf("key %q: got token %q, want %q", key, got.Token, want)
+		}
+	}
+}
+
+func TestFileWatcherContents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watched")
+	if err := ioutil.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+
+	w := newFileWatcher(path, time.Millisecond)
+	b, err := w.contents()
+	if err != nil || string(b) != "v1" {
+		t.Fatalf("got (%q, %v), want (%q, nil)", b, err, "v1")
+	}
+
+	// A rewrite observed within the watch interval doesn't take effect yet.
+	if err := ioutil.WriteFile(path, []byte("v2"), 0644); err != nil {
+		t.Fatalf("unexpected error rewriting file: %v", err)
+	}
+	b, err = w.contents()
+	if err != nil || string(b) != "v1" {
+		t.Fatalf("got (%q, %v), want cached (%q, nil)", b, err, "v1")
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	b, err = w.contents()
+	if err != nil || string(b) != "v2" {
+		t.Fatalf("got (%q, %v), want (%q, nil)", b, err, "v2")
+	}
+}
+
+func TestLoadKubeConfigDynamicRestConfigTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(path, []byte(testDataBase64), 0644); err != nil {
+		t.Fatalf("unexpected error writing token file: %v", err)
+	}
+
+	rawConfig := testKubeConfig
+	rawConfig.AuthInfos = append(append([]api.NamedAuthInfo{}, testKubeConfig.AuthInfos...), api.NamedAuthInfo{
+		Name:     "dynamic_token_file",
+		AuthInfo: api.AuthInfo{TokenFile: path},
+	})
+	rawConfig.Contexts = append(append([]api.NamedContext{}, testKubeConfig.Contexts...), api.NamedContext{
+		Name:    "dynamic_token_file",
+		Context: api.Context{Cluster: "default", AuthInfo: "dynamic_token_file"},
+	})
+
+	actual := KubeConfigLoader{rawConfig: rawConfig, skipConfigPersist: true}
+	WithDynamicRestConfig()(&actual)
+	WithFileWatchInterval(time.Millisecond)(&actual)
+
+	if err := actual.SetActiveContext("dynamic_token_file"); err != nil {
+		t.Fatalf("unexpected error setting active context: %v", err)
+	}
+	if err := actual.loadAuthentication(); err != nil {
+		t.Fatalf("unexpected error loading authentication: %v", err)
+	}
+
+	provider := actual.RestConfig().BearerTokenProvider()
+	if provider == nil {
+		t.Fatalf("expected a non-nil BearerTokenProvider in DynamicRestConfig mode")
+	}
+	want := fmt.Sprintf(bearerTokenFormat, testDataBase64)
+	if got, err := provider(); err != nil || got != want {
+		t.Fatalf("got (%q, %v), want (%q, nil)", got, err, want)
+	}
+
+	// Rewrite the token file on disk; the provider should observe the new bytes without
+	// SetActiveContext or loadAuthentication being called again.
+	if err := ioutil.WriteFile(path, []byte(testAnotherDataBase64), 0644); err != nil {
+		t.Fatalf("unexpected error rewriting token file: %v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+
+	want = fmt.Sprintf(bearerTokenFormat, testAnotherDataBase64)
+	if got, err := provider(); err != nil || got != want {
+		t.Fatalf("got (%q, %v), want (%q, nil) after rewrite", got, err, want)
+	}
+}
+
+func TestLoadKubeConfigDynamicRestConfigCertFiles(t *testing.T) {
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "ca.pem")
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+
+	write := func(path string, content []byte) {
+		t.Helper()
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			t.Fatalf("unexpected error writing %q: %v", path, err)
+		}
+	}
+	write(caPath, testCertAuthBase64)
+	write(certPath, testClientCertBase64)
+	write(keyPath, testClientKeyBase64)
+
+	rawConfig := testKubeConfig
+	rawConfig.Clusters = append(append([]api.NamedCluster{}, testKubeConfig.Clusters...), api.NamedCluster{
+		Name: "dynamic_cluster",
+		Cluster: api.Cluster{
+			Server:               testSSLServer,
+			CertificateAuthority: caPath,
+		},
+	})
+	rawConfig.AuthInfos = append(append([]api.NamedAuthInfo{}, testKubeConfig.AuthInfos...), api.NamedAuthInfo{
+		Name: "dynamic_cert_files",
+		AuthInfo: api.AuthInfo{
+			ClientCertificate: certPath,
+			ClientKey:         keyPath,
+		},
+	})
+	rawConfig.Contexts = append(append([]api.NamedContext{}, testKubeConfig.Contexts...), api.NamedContext{
+		Name:    "dynamic_cert_files",
+		Context: api.Context{Cluster: "dynamic_cluster", AuthInfo: "dynamic_cert_files"},
+	})
+
+	actual := KubeConfigLoader{rawConfig: rawConfig, skipConfigPersist: true}
+	WithDynamicRestConfig()(&actual)
+	WithFileWatchInterval(time.Millisecond)(&actual)
+
+	if err := actual.SetActiveContext("dynamic_cert_files"); err != nil {
+		t.Fatalf("unexpected error setting active context: %v", err)
+	}
+	actual.loadAuthentication()
+	if err := actual.loadClusterInfo(); err != nil {
+		t.Fatalf("unexpected error loading cluster info: %v", err)
+	}
+
+	rc := actual.RestConfig()
+	if string(rc.CACert()) != string(testCertAuthBase64) {
+		t.Fatalf("got CA %q, want %q", rc.CACert(), testCertAuthBase64)
+	}
+	if string(rc.ClientCert()) != string(testClientCertBase64) {
+		t.Fatalf("got client cert %q, want %q", rc.ClientCert(), testClientCertBase64)
+	}
+	if string(rc.ClientKey()) != string(testClientKeyBase64) {
+		t.Fatalf("got client key %q, want %q", rc.ClientKey(), testClientKeyBase64)
+	}
+
+	// Rewrite all three files on disk; RestConfig's getters (and, by extension,
+	// GetClientCertificate, which reads through ClientCert/ClientKey) should observe the
+	// new bytes without SetActiveContext or loadClusterInfo being called again.
+	write(caPath, []byte("rotated-ca"))
+	write(certPath, []byte("rotated-cert"))
+	write(keyPath, []byte("rotated-key"))
+	time.Sleep(2 * time.Millisecond)
+
+	if got := string(rc.CACert()); got != "rotated-ca" {
+		t.Errorf("got CA %q after rotation, want %q", got, "rotated-ca")
+	}
+	if got := string(rc.ClientCert()); got != "rotated-cert" {
+		t.Errorf("got client cert %q after rotation, want %q", got, "rotated-cert")
+	}
+	if got := string(rc.ClientKey()); got != "rotated-key" {
+		t.Errorf("got client key %q after rotation, want %q", got, "rotated-key")
+	}
+}
+
+func TestLoadKubeConfigCredentialCacheHit(t *testing.T) {
+	cache, err := NewFileCredentialCache(WithCredentialCachePath(filepath.Join(t.TempDir(), "credentials.yaml")))
+	if err != nil {
+		t.Fatalf("unexpected error constructing cache: %v", err)
+	}
+
+	actual := KubeConfigLoader{
+		rawConfig:         testKubeConfig,
+		skipConfigPersist: true,
+		gcLoader:          FakeGoogleCredentialLoaderNoRefresh{},
+	}
+	WithCredentialCache(cache)(&actual)
+
+	if err := actual.SetActiveContext("expired_gcp"); err != nil {
+		t.Fatalf("unexpected error setting active context: %v", err)
+	}
+	ctx, err := actual.currentContext()
+	if err != nil {
+		t.Fatalf("unexpected error reading active context: %v", err)
+	}
+	cacheKey := credentialCacheKey(ctx.AuthInfo, googleAuthProviderName, "")
+	if err := cache.Set(cacheKey, &CachedCredential{Token: testAnotherDataBase64, Expiry: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("unexpected error seeding cache: %v", err)
+	}
+
+	// FakeGoogleCredentialLoaderNoRefresh errors if it's invoked, so this only passes if
+	// the cached token is used instead.
+	if err := actual.loadAuthentication(); err != nil {
+		t.Fatalf("unexpected error loading authentication: %v", err)
+	}
+	want := fmt.Sprintf(bearerTokenFormat, testAnotherDataBase64)
+	if actual.restConfig.token != want {
+		t.Errorf("got token %q, want %q", actual.restConfig.token, want)
+	}
+}
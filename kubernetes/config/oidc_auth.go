@@ -0,0 +1,248 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"k8s.io/go/kubernetes/config/api"
+)
+
+const oidcAuthProviderName = "oidc"
+
+// OIDCLoader performs the OAuth2 refresh-token grant for the `oidc` AuthProvider,
+// analogous to GoogleCredentialLoader for the `gcp` provider.
+type OIDCLoader interface {
+	// RefreshOIDCToken exchanges provider's refresh-token for a new id-token, returning
+	// the new id-token and the refresh-token to persist (providers may rotate it).
+	RefreshOIDCToken(provider *api.AuthProviderConfig) (idToken, refreshToken string, err error)
+}
+
+// DefaultOIDCLoader discovers the issuer's token endpoint from its
+// /.well-known/openid-configuration document and performs a refresh-token grant
+// against it using the provider's client-id/client-secret.
+type DefaultOIDCLoader struct{}
+
+type oidcDiscoveryDocument struct {
+	TokenEndpoint string `json:"token_endpoint"`
+}
+
+// RefreshOIDCToken implements OIDCLoader.
+func (l DefaultOIDCLoader) RefreshOIDCToken(provider *api.AuthProviderConfig) (string, string, error) {
+	cfg := provider.Config
+	issuer := cfg["idp-issuer-url"]
+	if issuer == "" {
+		return "", "", fmt.Errorf("oidc auth provider is missing idp-issuer-url")
+	}
+
+	client, err := oidcHTTPClient(cfg)
+	if err != nil {
+		return "", "", err
+	}
+
+	discovery, err := fetchOIDCDiscovery(client, issuer)
+	if err != nil {
+		return "", "", err
+	}
+
+	oauthCfg := oauth2.Config{
+		ClientID:     cfg["client-id"],
+		ClientSecret: cfg["client-secret"],
+		Endpoint:     oauth2.Endpoint{TokenURL: discovery.TokenEndpoint},
+	}
+	if extraScopes := cfg["extra-scopes"]; extraScopes != "" {
+		oauthCfg.Scopes = strings.Split(extraScopes, ",")
+	}
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client)
+	tok, err := oauthCfg.TokenSource(ctx, &oauth2.Token{RefreshToken: cfg["refresh-token"]}).Token()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to refresh oidc token: %v", err)
+	}
+
+	idToken, _ := tok.Extra("id_token").(string)
+	if idToken == "" {
+		return "", "", fmt.Errorf("oidc token response did not include an id_token")
+	}
+
+	refreshToken := tok.RefreshToken
+	if refreshToken == "" {
+		refreshToken = cfg["refresh-token"]
+	}
+
+	return idToken, refreshToken, nil
+}
+
+// oidcHTTPClient builds an HTTP client trusting the provider's configured CA, falling
+// back to the system trust store when none is set.
+func oidcHTTPClient(cfg map[string]string) (*http.Client, error) {
+	var caPEM []byte
+	switch {
+	case cfg["idp-certificate-authority-data"] != "":
+		decoded, err := base64.StdEncoding.DecodeString(cfg["idp-certificate-authority-data"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode idp-certificate-authority-data: %v", err)
+		}
+		caPEM = decoded
+	case cfg["idp-certificate-authority"] != "":
+		b, err := dataOrFile(nil, cfg["idp-certificate-authority"])
+		if err != nil {
+			return nil, err
+		}
+		caPEM = b
+	}
+
+	if len(caPEM) == 0 {
+		return http.DefaultClient, nil
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("failed to parse idp-certificate-authority PEM")
+	}
+	return &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+	}, nil
+}
+
+func fetchOIDCDiscovery(client *http.Client, issuer string) (*oidcDiscoveryDocument, error) {
+	resp, err := client.Get(strings.TrimRight(issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch oidc discovery document: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var doc oidcDiscoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse oidc discovery document: %v", err)
+	}
+	if doc.TokenEndpoint == "" {
+		return nil, fmt.Errorf("oidc discovery document is missing token_endpoint")
+	}
+	return &doc, nil
+}
+
+// jwtExpiry decodes the unverified "exp" claim out of a JWT's payload segment. The
+// signature isn't checked: the id-token was already validated by the IdP when it was
+// issued, and we only need to know when to ask for a new one.
+func jwtExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("malformed id-token")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode id-token payload: %v", err)
+	}
+
+	var claims struct {
+		Exp float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse id-token claims: %v", err)
+	}
+	return time.Unix(int64(claims.Exp), 0).UTC(), nil
+}
+
+// oidcAuthProviderLoader registers the `oidc` AuthProvider under the AuthProviderLoader
+// interface.
+type oidcAuthProviderLoader struct{}
+
+// LoadAuthProvider implements AuthProviderLoader.
+func (oidcAuthProviderLoader) LoadAuthProvider(l *KubeConfigLoader, provider *api.AuthProviderConfig) error {
+	return l.loadOIDCAuth(provider)
+}
+
+// loadOIDCAuth implements the `oidc` AuthProvider: reuse the cached id-token unless it's
+// expired, in which case ask oidcLoader to refresh it and update the cached config.
+func (l *KubeConfigLoader) loadOIDCAuth(provider *api.AuthProviderConfig) error {
+	idToken := provider.Config["id-token"]
+	expired := oidcIDTokenExpired(idToken)
+
+	ctx, err := l.currentContext()
+	if err != nil {
+		return err
+	}
+	cacheKey := credentialCacheKey(ctx.AuthInfo, oidcAuthProviderName, provider.Config["idp-issuer-url"]+"|"+provider.Config["client-id"])
+
+	if expired {
+		if cached, ok := l.credentialCacheLookup(cacheKey); ok {
+			idToken = cached.Token
+			expired = false
+		}
+	}
+
+	if expired {
+		oidcLoader := l.oidcLoader
+		if oidcLoader == nil {
+			oidcLoader = DefaultOIDCLoader{}
+		}
+		newIDToken, newRefreshToken, err := oidcLoader.RefreshOIDCToken(provider)
+		if err != nil {
+			return fmt.Errorf("failed to refresh oidc credentials: %v", err)
+		}
+		idToken = newIDToken
+
+		expiry, err := jwtExpiry(newIDToken)
+		if err == nil {
+			l.credentialCacheStore(cacheKey, &CachedCredential{Token: newIDToken, Expiry: expiry})
+		}
+
+		if provider.Config == nil {
+			provider.Config = map[string]string{}
+		}
+		provider.Config["id-token"] = newIDToken
+		provider.Config["refresh-token"] = newRefreshToken
+
+		if !l.skipConfigPersist {
+			authInfo, err := l.currentAuthInfo()
+			if err != nil {
+				return err
+			}
+			if err := setUserWithName(l.rawConfig.AuthInfos, ctx.AuthInfo, authInfo); err != nil {
+				return err
+			}
+			if err := l.persist(); err != nil {
+				return err
+			}
+		}
+	}
+
+	l.restConfig.token = fmt.Sprintf(bearerFormat, idToken)
+	return nil
+}
+
+func oidcIDTokenExpired(idToken string) bool {
+	if idToken == "" {
+		return true
+	}
+	exp, err := jwtExpiry(idToken)
+	if err != nil {
+		return true
+	}
+	return !exp.After(time.Now().UTC().Add(expirySkewPreventionDelay))
+}
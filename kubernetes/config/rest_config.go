@@ -0,0 +1,141 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// BearerTokenProvider returns a bearer Authorization header value read fresh from disk on
+// each call. It's set on a RestConfig in place of a static Token when the active
+// AuthInfo's TokenFile is resolved in DynamicRestConfig mode (see WithDynamicRestConfig),
+// so a token rotated on disk (e.g. a projected service account token) takes effect
+// without rebuilding the client.
+type BearerTokenProvider func() (string, error)
+
+// RestConfig holds the connection and authentication information resolved from a
+// kubeconfig, ready to be used to build a generated client's Configuration.
+type RestConfig struct {
+	basePath string
+	host     string
+	scheme   string
+
+	token               string
+	bearerTokenProvider BearerTokenProvider
+
+	caCert     []byte
+	clientCert []byte
+	clientKey  []byte
+
+	// caCertWatcher, clientCertWatcher, and clientKeyWatcher are set in place of reading
+	// caCert/clientCert/clientKey once when the corresponding kubeconfig field is a file
+	// path and the loader is in DynamicRestConfig mode.
+	caCertWatcher     *fileWatcher
+	clientCertWatcher *fileWatcher
+	clientKeyWatcher  *fileWatcher
+
+	skipTLSVerify bool
+}
+
+// BasePath returns the server URL with any trailing slash removed.
+func (c RestConfig) BasePath() string {
+	return c.basePath
+}
+
+// Host returns the server's host:port.
+func (c RestConfig) Host() string {
+	return c.host
+}
+
+// Scheme returns the server URL's scheme (http or https).
+func (c RestConfig) Scheme() string {
+	return c.scheme
+}
+
+// Token returns the resolved Authorization header value, e.g. "Bearer <token>", as of
+// the last loadAuthentication call. In DynamicRestConfig mode, prefer
+// BearerTokenProvider when it's non-nil, since it reflects the token file's current
+// contents rather than a point-in-time read.
+func (c RestConfig) Token() string {
+	return c.token
+}
+
+// BearerTokenProvider returns the closure that re-reads the Authorization header value
+// from the active AuthInfo's TokenFile, or nil if the loader isn't in DynamicRestConfig
+// mode or the active AuthInfo doesn't use a TokenFile.
+func (c RestConfig) BearerTokenProvider() BearerTokenProvider {
+	return c.bearerTokenProvider
+}
+
+// CACert returns the cluster's certificate authority data, if any. In DynamicRestConfig
+// mode with a file-based CertificateAuthority, this re-reads the file (see
+// WithFileWatchInterval) so a certificate rotated on disk takes effect.
+func (c RestConfig) CACert() []byte {
+	if c.caCertWatcher != nil {
+		if b, err := c.caCertWatcher.contents(); err == nil {
+			return b
+		}
+	}
+	return c.caCert
+}
+
+// ClientCert returns the client certificate data used for mutual TLS, if any. In
+// DynamicRestConfig mode with a file-based ClientCertificate, this re-reads the file so a
+// certificate rotated on disk takes effect.
+func (c RestConfig) ClientCert() []byte {
+	if c.clientCertWatcher != nil {
+		if b, err := c.clientCertWatcher.contents(); err == nil {
+			return b
+		}
+	}
+	return c.clientCert
+}
+
+// ClientKey returns the client private key data used for mutual TLS, if any. In
+// DynamicRestConfig mode with a file-based ClientKey, this re-reads the file so a key
+// rotated on disk takes effect.
+func (c RestConfig) ClientKey() []byte {
+	if c.clientKeyWatcher != nil {
+		if b, err := c.clientKeyWatcher.contents(); err == nil {
+			return b
+		}
+	}
+	return c.clientKey
+}
+
+// GetClientCertificate matches the signature expected by tls.Config.GetClientCertificate.
+// In DynamicRestConfig mode with a file-based ClientCertificate and ClientKey, it
+// re-reads both files on every call, so a certificate rotated on disk (e.g. by
+// cert-manager) takes effect without rebuilding the client; otherwise it parses the
+// certificate and key resolved at load time.
+func (c RestConfig) GetClientCertificate(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	certPEM, keyPEM := c.ClientCert(), c.ClientKey()
+	if len(certPEM) == 0 || len(keyPEM) == 0 {
+		return nil, fmt.Errorf("no client certificate is configured")
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse client certificate: %v", err)
+	}
+	return &cert, nil
+}
+
+// SkipTLSVerify reports whether server certificate verification should be skipped.
+func (c RestConfig) SkipTLSVerify() bool {
+	return c.skipTLSVerify
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+)
+
+// TLSConfig holds TLS hardening options layered on top of a RestConfig's certificate
+// material when building the *tls.Config used by the generated client's HTTP
+// transport.
+type TLSConfig struct {
+	// MinVersion is the minimum TLS version to negotiate. Defaults to tls.VersionTLS12.
+	MinVersion uint16
+	// MaxVersion is the maximum TLS version to negotiate. Zero means no cap.
+	MaxVersion uint16
+	// CipherSuites restricts negotiation to this allow-list. The Go runtime always
+	// chooses a safe suite for TLS 1.3 regardless of this setting.
+	CipherSuites []uint16
+	// ServerName overrides the server name used for SNI and certificate verification.
+	ServerName string
+	// NextProtos is the list of application protocols offered via ALPN.
+	NextProtos []string
+}
+
+// defaultStrictCipherSuites is the modern AEAD-only cipher suite set WithStrictTLS
+// restricts TLS 1.2 negotiation to.
+var defaultStrictCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_AES_128_GCM_SHA256,
+	tls.TLS_AES_256_GCM_SHA384,
+	tls.TLS_CHACHA20_POLY1305_SHA256,
+}
+
+// WithStrictTLS restricts the loader to TLS 1.2+ and a modern AEAD cipher suite set, and
+// makes loadClusterInfo refuse to load a cluster configured with
+// insecure-skip-tls-verify.
+func WithStrictTLS() KubeConfigLoaderOption {
+	return func(l *KubeConfigLoader) {
+		l.strictTLS = true
+		l.tlsConfig.MinVersion = tls.VersionTLS12
+		l.tlsConfig.CipherSuites = defaultStrictCipherSuites
+	}
+}
+
+// WithTLSServerName overrides the server name used for SNI and certificate
+// verification, e.g. when connecting through an address that doesn't match the
+// cluster's certificate.
+func WithTLSServerName(name string) KubeConfigLoaderOption {
+	return func(l *KubeConfigLoader) {
+		l.tlsConfig.ServerName = name
+	}
+}
+
+// WithTLSNextProtos sets the application protocol list negotiated via ALPN.
+func WithTLSNextProtos(protos ...string) KubeConfigLoaderOption {
+	return func(l *KubeConfigLoader) {
+		l.tlsConfig.NextProtos = protos
+	}
+}
+
+// TLSConfig builds a *tls.Config from the loader's resolved RestConfig and TLS
+// hardening options. It must be called after loadClusterInfo.
+func (l *KubeConfigLoader) TLSConfig() (*tls.Config, error) {
+	minVersion := l.tlsConfig.MinVersion
+	if minVersion == 0 {
+		minVersion = tls.VersionTLS12
+	}
+
+	cfg := &tls.Config{
+		InsecureSkipVerify: l.restConfig.skipTLSVerify,
+		ServerName:         l.tlsConfig.ServerName,
+		NextProtos:         l.tlsConfig.NextProtos,
+		MinVersion:         minVersion,
+		MaxVersion:         l.tlsConfig.MaxVersion,
+		CipherSuites:       l.tlsConfig.CipherSuites,
+	}
+
+	if caCert := l.restConfig.CACert(); len(caCert) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to parse certificate authority data as PEM")
+		}
+		cfg.RootCAs = pool
+	}
+
+	if len(l.restConfig.ClientCert()) > 0 && len(l.restConfig.ClientKey()) > 0 {
+		cfg.GetClientCertificate = l.restConfig.GetClientCertificate
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"k8s.io/go/kubernetes/config/api"
+)
+
+// dataOrFile returns data if it is non-empty, otherwise it reads and returns the
+// contents of file. It returns nil, nil if neither is set.
+func dataOrFile(data []byte, file string) ([]byte, error) {
+	if len(data) > 0 {
+		return data, nil
+	}
+	if file == "" {
+		return nil, nil
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data or file: %v", err)
+	}
+	return b, nil
+}
+
+// setUserWithName overwrites, in place, the AuthInfo belonging to the named user.
+func setUserWithName(authInfos []api.NamedAuthInfo, name string, user *api.AuthInfo) error {
+	for i := range authInfos {
+		if authInfos[i].Name == name {
+			authInfos[i].AuthInfo = *user
+			return nil
+		}
+	}
+	return fmt.Errorf("user %q not found in kubeconfig", name)
+}